@@ -0,0 +1,149 @@
+package testframework
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"k8s.io/client-go/rest"
+)
+
+// AuthMode selects how CreateUserWithAuth authenticates the *User it
+// produces. Implementations mutate a base admin rest.Config into one that
+// authenticates as the target user the same way a real OpenShift install
+// would (service-account token, client cert, OIDC, or impersonation),
+// rather than only exercising the bearer-token path.
+type AuthMode interface {
+	// Apply returns a rest.Config derived from adminConfig that
+	// authenticates as username using this mode.
+	Apply(m *Master, username string, adminConfig *rest.Config) (*rest.Config, error)
+}
+
+// BearerTokenAuth mints a service-account style bearer token for the user,
+// via the same GetClientForUser path CreateUser has always used.
+type BearerTokenAuth struct{}
+
+func (BearerTokenAuth) Apply(m *Master, username string, adminConfig *rest.Config) (*rest.Config, error) {
+	_, user, err := GetClientForUser(adminConfig, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a token for the user %s: %v", username, err)
+	}
+	return UserClientConfig(adminConfig, user.BearerToken), nil
+}
+
+// ClientCertAuth issues a short-lived client certificate signed by the
+// cluster CA and authenticates via rest.Config.TLSClientConfig, matching
+// OpenShift's cert-based kubeconfig flow.
+type ClientCertAuth struct {
+	// TTL is how long the issued certificate is valid for. Defaults to
+	// one hour when zero.
+	TTL time.Duration
+}
+
+func (a ClientCertAuth) Apply(m *Master, username string, adminConfig *rest.Config) (*rest.Config, error) {
+	ttl := a.TTL
+	if ttl == 0 {
+		ttl = time.Hour
+	}
+
+	certPEM, keyPEM, err := IssueClientCert(m, username, ttl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue a client cert for the user %s: %v", username, err)
+	}
+
+	config := rest.CopyConfig(adminConfig)
+	config.BearerToken = ""
+	config.BearerTokenFile = ""
+	config.TLSClientConfig.CertData = certPEM
+	config.TLSClientConfig.KeyData = keyPEM
+	return config, nil
+}
+
+// OIDCAuth mints an ID token from an in-test OIDC issuer and authenticates
+// via rest.Config.BearerTokenFile, the same shape dex/pinniped-style
+// kubeconfigs use.
+//
+// This only exercises a real authentication path against a MasterProcess
+// (a real cluster's kube-apiserver started with --oidc-issuer-url and
+// --oidc-ca-file pointed at the in-test issuer). An EmbeddedMaster's
+// apiserver is never given those flags, so the token Apply mints would be
+// rejected rather than accepted. Apply fails
+// fast against an EmbeddedMaster instead of handing back a config that
+// looks valid but can't authenticate anything.
+type OIDCAuth struct {
+	// IssuerURL is the in-test OIDC issuer to mint tokens from. Defaults
+	// to Master.OIDCIssuerURL() when empty.
+	IssuerURL string
+}
+
+func (a OIDCAuth) Apply(m *Master, username string, adminConfig *rest.Config) (*rest.Config, error) {
+	if _, embedded := m.container.(*EmbeddedMaster); embedded {
+		return nil, fmt.Errorf("OIDCAuth requires a real cluster (MasterProcess): EmbeddedMaster's apiserver isn't configured with --oidc-issuer-url/--oidc-ca-file")
+	}
+
+	issuerURL := a.IssuerURL
+	if issuerURL == "" {
+		issuerURL = m.OIDCIssuerURL()
+	}
+
+	idToken, err := MintOIDCToken(issuerURL, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mint an OIDC token for the user %s: %v", username, err)
+	}
+
+	f, err := ioutil.TempFile("", "image-registry-oidc-token-")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.WriteString(idToken); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	m.t.Cleanup(func() { os.Remove(f.Name()) })
+
+	config := rest.CopyConfig(adminConfig)
+	config.BearerToken = ""
+	config.BearerTokenFile = f.Name()
+	config.TLSClientConfig.CertData = nil
+	config.TLSClientConfig.KeyData = nil
+	config.TLSClientConfig.CertFile = ""
+	config.TLSClientConfig.KeyFile = ""
+	return config, nil
+}
+
+// ImpersonationAuth keeps the admin credentials in place and instead relies
+// on the apiserver's impersonation support, setting rest.Config.Impersonate
+// to the target user and groups.
+type ImpersonationAuth struct {
+	Groups []string
+}
+
+func (a ImpersonationAuth) Apply(m *Master, username string, adminConfig *rest.Config) (*rest.Config, error) {
+	config := rest.CopyConfig(adminConfig)
+	config.Impersonate = rest.ImpersonationConfig{
+		UserName: username,
+		Groups:   a.Groups,
+	}
+	return config, nil
+}
+
+// CreateUserWithAuth is like CreateUser but authenticates the returned *User
+// using the given AuthMode instead of always minting a bearer token. This
+// lets registry auth tests exercise the client-cert and OIDC kubeconfig
+// shapes real OpenShift installations hit, not just the service-account
+// token path.
+func (m *Master) CreateUserWithAuth(username string, mode AuthMode) *User {
+	config, err := mode.Apply(m, username, m.AdminKubeConfig())
+	if err != nil {
+		m.t.Fatalf("failed to authenticate the user %s: %v", username, err)
+	}
+
+	return &User{
+		Name:       username,
+		kubeConfig: config,
+	}
+}
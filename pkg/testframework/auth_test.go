@@ -0,0 +1,16 @@
+package testframework
+
+import (
+	"testing"
+
+	"k8s.io/client-go/rest"
+)
+
+func TestOIDCAuthRejectsEmbeddedMaster(t *testing.T) {
+	m := &Master{t: t, container: &EmbeddedMaster{}}
+
+	_, err := (OIDCAuth{}).Apply(m, "alice", &rest.Config{})
+	if err == nil {
+		t.Fatal("expected OIDCAuth.Apply against an EmbeddedMaster to fail, got nil")
+	}
+}
@@ -0,0 +1,106 @@
+package testframework
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// ClientCAIssuer is implemented by a MasterInterface that controls its own
+// client CA and can therefore sign short-lived client certificates for it,
+// such as EmbeddedMaster. Masters backed by a pre-existing external cluster
+// (MasterProcess) don't have the CA's private key and so don't implement
+// this.
+type ClientCAIssuer interface {
+	IssueClientCert(commonName string, ttl time.Duration) (certPEM, keyPEM []byte, err error)
+}
+
+// testCA is a minimal self-signed CA used to sign short-lived client
+// certificates for ClientCertAuth. EmbeddedMaster configures the apiserver
+// it starts to trust this CA's certificate as a client CA.
+type testCA struct {
+	cert    *x509.Certificate
+	certPEM []byte
+	key     *rsa.PrivateKey
+}
+
+func newTestCA() (*testCA, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "image-registry-test-client-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to self-sign CA certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+
+	return &testCA{
+		cert:    cert,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:     key,
+	}, nil
+}
+
+// IssueClientCert signs a short-lived client certificate for commonName,
+// valid for ttl, and returns it as a PEM cert/key pair.
+func (ca *testCA) IssueClientCert(commonName string, ttl time.Duration) (certPEM, keyPEM []byte, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate client key: %v", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(ttl),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign client certificate for %s: %v", commonName, err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return certPEM, keyPEM, nil
+}
+
+// IssueClientCert asks m's underlying master to sign a short-lived client
+// certificate for username. Only masters that control their own client CA
+// (currently EmbeddedMaster) support this.
+func IssueClientCert(m *Master, username string, ttl time.Duration) (certPEM, keyPEM []byte, err error) {
+	issuer, ok := m.container.(ClientCAIssuer)
+	if !ok {
+		return nil, nil, fmt.Errorf("the current master does not control a client CA and cannot issue client certificates")
+	}
+	return issuer.IssueClientCert(username, ttl)
+}
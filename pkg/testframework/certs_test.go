@@ -0,0 +1,76 @@
+package testframework
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+func TestTestCAIssueClientCert(t *testing.T) {
+	ca, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA: %v", err)
+	}
+
+	certPEM, keyPEM, err := ca.IssueClientCert("alice", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueClientCert: %v", err)
+	}
+
+	certBlock, _ := pem.Decode(certPEM)
+	if certBlock == nil {
+		t.Fatalf("certPEM did not decode as PEM")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse issued certificate: %v", err)
+	}
+	if cert.Subject.CommonName != "alice" {
+		t.Errorf("cert CommonName = %q, want %q", cert.Subject.CommonName, "alice")
+	}
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	if keyBlock == nil {
+		t.Fatalf("keyPEM did not decode as PEM")
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		t.Errorf("issued certificate does not verify against its issuing CA: %v", err)
+	}
+}
+
+func TestTestCAIssueClientCertRejectsForeignCA(t *testing.T) {
+	ca1, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA: %v", err)
+	}
+	ca2, err := newTestCA()
+	if err != nil {
+		t.Fatalf("newTestCA: %v", err)
+	}
+
+	certPEM, _, err := ca1.IssueClientCert("alice", time.Hour)
+	if err != nil {
+		t.Fatalf("IssueClientCert: %v", err)
+	}
+	certBlock, _ := pem.Decode(certPEM)
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse issued certificate: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca2.cert)
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err == nil {
+		t.Errorf("expected a cert issued by ca1 to fail verification against ca2, but it succeeded")
+	}
+}
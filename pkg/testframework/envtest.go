@@ -0,0 +1,164 @@
+package testframework
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// EmbeddedMaster runs etcd and a stock kube-apiserver as child processes of
+// the test binary via envtest,
+// so that `go test ./...` can exercise registry behavior that only needs
+// native Kubernetes types without a real cluster. It does NOT provide
+// imagestreams, projects, or authorization objects: those API groups are
+// served on real clusters by openshift-apiserver (image/project) or a
+// virtual, RBAC-backed storage layer (authorization) rather than by CRDs, so
+// there is no CRD manifest that would make an envtest-loaded apiserver
+// understand them, and registering the Go types against this scheme without
+// real serving support behind them would just fail client calls at request
+// time instead of at startup. Tests that need those objects should run
+// against MasterProcess (a real cluster) instead; EmbeddedMaster configures
+// the apiserver to trust a per-run client CA so ClientCertAuth has something
+// to sign against for the native-type surface it does serve.
+type EmbeddedMaster struct {
+	env        *envtest.Environment
+	kubeconfig string
+	clientCA   *testCA
+}
+
+// StartEmbeddedMaster boots a local control plane and writes an admin
+// kubeconfig to a temp directory. The returned MasterInterface's Stop() tears
+// the apiserver and etcd processes down.
+func StartEmbeddedMaster(t *testing.T) (MasterInterface, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+
+	clientCA, err := newTestCA()
+	if err != nil {
+		return nil, err
+	}
+	clientCAFile, err := writeTempFile(t, "image-registry-client-ca-", clientCA.certPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &envtest.Environment{
+		Scheme: scheme,
+	}
+	env.ControlPlane.GetAPIServer().Configure().Append("client-ca-file", clientCAFile)
+
+	cfg, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start embedded master: %v", err)
+	}
+
+	kubeconfigPath, err := writeAdminKubeConfig(t, cfg)
+	if err != nil {
+		_ = env.Stop()
+		return nil, err
+	}
+
+	return &EmbeddedMaster{
+		env:        env,
+		kubeconfig: kubeconfigPath,
+		clientCA:   clientCA,
+	}, nil
+}
+
+// IssueClientCert implements ClientCAIssuer by signing against the CA this
+// embedded master configured its apiserver to trust.
+func (m *EmbeddedMaster) IssueClientCert(commonName string, ttl time.Duration) ([]byte, []byte, error) {
+	return m.clientCA.IssueClientCert(commonName, ttl)
+}
+
+func (m *EmbeddedMaster) AdminKubeConfigPath() string {
+	return m.kubeconfig
+}
+
+func (m *EmbeddedMaster) WaitHealthz(configDir string) error {
+	config, err := ConfigFromFile(m.kubeconfig)
+	if err != nil {
+		return err
+	}
+	rt, err := rest.TransportFor(config)
+	if err != nil {
+		return err
+	}
+	return WaitHTTP(rt, fmt.Sprintf("%s/healthz", config.Host))
+}
+
+func (m *EmbeddedMaster) Stop() error {
+	return m.env.Stop()
+}
+
+// writeTempFile writes data to a new file in the system temp directory,
+// removed when t's cleanup runs, and returns its path.
+func writeTempFile(t *testing.T, prefix string, data []byte) (string, error) {
+	f, err := ioutil.TempFile("", prefix)
+	if err != nil {
+		return "", err
+	}
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeAdminKubeConfig materializes the envtest rest.Config as a kubeconfig
+// file on disk, since the rest of the test framework (and the binaries it
+// shells out to) expect a KUBECONFIG path rather than an in-memory config.
+func writeAdminKubeConfig(t *testing.T, cfg *rest.Config) (string, error) {
+	dir, err := ioutil.TempDir("", "image-registry-envtest-")
+	if err != nil {
+		return "", err
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+
+	kubeconfig := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{
+			"envtest": {
+				Server:                   cfg.Host,
+				CertificateAuthorityData: cfg.CAData,
+			},
+		},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{
+			"admin": {
+				ClientCertificateData: cfg.CertData,
+				ClientKeyData:         cfg.KeyData,
+				Token:                 cfg.BearerToken,
+			},
+		},
+		Contexts: map[string]*clientcmdapi.Context{
+			"envtest": {
+				Cluster:  "envtest",
+				AuthInfo: "admin",
+			},
+		},
+		CurrentContext: "envtest",
+	}
+
+	path := dir + "/admin.kubeconfig"
+	if err := clientcmd.WriteToFile(kubeconfig, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,228 @@
+package testframework
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+// FaultSpec configures the fault-injecting RoundTripper installed by
+// WithFaultInjection. Each field is independently optional; a zero-value
+// FaultSpec injects nothing and behaves like a plain passthrough transport.
+type FaultSpec struct {
+	// Scenarios are evaluated in order for every request. Each scenario
+	// that matches applies its Latency and then its Fault; the first Fault
+	// that handles the request ends evaluation, while a scenario with no
+	// Fault, or whose Fault lets the request proceed, passes it on to the
+	// scenarios after it.
+	Scenarios []FaultScenario
+}
+
+// FaultScenario is one entry in the FaultSpec DSL, e.g. "drop every third
+// PATCH /v2/*/blobs/uploads/ for 30s":
+//
+//	FaultScenario{
+//		Method:      "PATCH",
+//		URLPattern:  `/v2/.*/blobs/uploads/.*`,
+//		EveryNth:    3,
+//		Duration:    30 * time.Second,
+//		Fault:       DropConnection(),
+//	}
+type FaultScenario struct {
+	// Method restricts the scenario to requests with this HTTP method.
+	// Empty matches any method.
+	Method string
+	// URLPattern is matched against the request URL path via
+	// regexp.MatchString. Empty matches any path.
+	URLPattern string
+	// EveryNth, if non-zero, applies Fault to only every Nth matching
+	// request instead of every one.
+	EveryNth int
+	// Probability, if non-zero, applies Fault to a matching request with
+	// this probability in [0,1] instead of deterministically.
+	Probability float64
+	// Latency delays the request by this duration before it is sent.
+	Latency time.Duration
+	// Duration bounds how long after the transport is installed this
+	// scenario stays active. Zero means it never expires.
+	Duration time.Duration
+
+	// Fault is the failure behavior to apply to a matching request.
+	Fault FaultFunc
+
+	re      *regexp.Regexp
+	start   time.Time
+	counter uint64
+}
+
+// FaultFunc implements a single failure mode against an in-flight request.
+// It either short-circuits with its own response/error, or returns
+// (nil, nil, false) to let the request proceed untouched.
+type FaultFunc func(req *http.Request, next http.RoundTripper) (*http.Response, error, bool)
+
+// Prob5xx returns a FaultFunc that fails the request with the given status
+// code instead of sending it.
+func Prob5xx(status int) FaultFunc {
+	return func(req *http.Request, next http.RoundTripper) (*http.Response, error, bool) {
+		return &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Body:       ioutil.NopCloser(bytes.NewReader(nil)),
+			Header:     make(http.Header),
+			Request:    req,
+		}, nil, true
+	}
+}
+
+// DropConnection returns a FaultFunc that fails the request as if the peer
+// had reset the connection.
+func DropConnection() FaultFunc {
+	return func(req *http.Request, next http.RoundTripper) (*http.Response, error, bool) {
+		return nil, fmt.Errorf("fault injection: connection reset by peer"), true
+	}
+}
+
+// MidBodyEOF returns a FaultFunc that lets the request through but truncates
+// the response body after n bytes, surfacing an unexpected EOF to the
+// caller — useful for covering blob PUT/GET resume behavior against a
+// flaky object store.
+func MidBodyEOF(n int64) FaultFunc {
+	return func(req *http.Request, next http.RoundTripper) (*http.Response, error, bool) {
+		resp, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err, true
+		}
+		resp.Body = &truncatingReader{r: resp.Body, remaining: n}
+		return resp, nil, true
+	}
+}
+
+// truncatingReader returns io.ErrUnexpectedEOF once remaining bytes have
+// been read, then stops yielding any further data. It must be used through
+// a pointer: remaining needs to persist across Read calls made through the
+// io.ReadCloser interface resp.Body is stored as, which a value receiver
+// cannot do since each call would operate on its own copy.
+type truncatingReader struct {
+	r         io.ReadCloser
+	remaining int64
+}
+
+func (t *truncatingReader) Read(p []byte) (int, error) {
+	if t.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > t.remaining {
+		p = p[:t.remaining]
+	}
+	n, err := t.r.Read(p)
+	t.remaining -= int64(n)
+	return n, err
+}
+
+func (t *truncatingReader) Close() error {
+	return t.r.Close()
+}
+
+// TLSHandshakeStall returns a FaultFunc that sleeps for d before letting the
+// request through, intended to be tuned against the registry transport's
+// existing 10s TLSHandshakeTimeout to exercise handshake-timeout handling.
+func TLSHandshakeStall(d time.Duration) FaultFunc {
+	return func(req *http.Request, next http.RoundTripper) (*http.Response, error, bool) {
+		time.Sleep(d)
+		return nil, nil, false
+	}
+}
+
+// faultRoundTripper wraps a base http.RoundTripper and applies every
+// matching, still-active FaultScenario to each outgoing request in order,
+// stopping at the first one whose Fault handles the request.
+type faultRoundTripper struct {
+	base      http.RoundTripper
+	scenarios []*FaultScenario
+}
+
+// WrapTransport wraps rt with the fault injection described by spec. A nil
+// or empty FaultSpec returns rt unchanged.
+func WrapTransport(rt http.RoundTripper, spec FaultSpec) http.RoundTripper {
+	if len(spec.Scenarios) == 0 {
+		return rt
+	}
+
+	now := time.Now()
+	scenarios := make([]*FaultScenario, len(spec.Scenarios))
+	for i := range spec.Scenarios {
+		s := spec.Scenarios[i]
+		if s.URLPattern != "" {
+			s.re = regexp.MustCompile(s.URLPattern)
+		}
+		s.start = now
+		scenarios[i] = &s
+	}
+
+	return &faultRoundTripper{base: rt, scenarios: scenarios}
+}
+
+func (f *faultRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	for _, s := range f.scenarios {
+		if !s.matches(req) {
+			continue
+		}
+		if s.Latency > 0 {
+			time.Sleep(s.Latency)
+		}
+		if s.Fault == nil {
+			continue
+		}
+		if resp, err, handled := s.Fault(req, f.base); handled {
+			return resp, err
+		}
+	}
+	return f.base.RoundTrip(req)
+}
+
+func (s *FaultScenario) matches(req *http.Request) bool {
+	if s.Fault == nil && s.Latency == 0 {
+		return false
+	}
+	if s.Method != "" && req.Method != s.Method {
+		return false
+	}
+	if s.re != nil && !s.re.MatchString(req.URL.Path) {
+		return false
+	}
+	if s.Duration != 0 && time.Since(s.start) > s.Duration {
+		return false
+	}
+	if s.EveryNth > 0 {
+		n := atomic.AddUint64(&s.counter, 1)
+		return n%uint64(s.EveryNth) == 0
+	}
+	if s.Probability > 0 {
+		return rand.Float64() < s.Probability
+	}
+	return true
+}
+
+// WithFaultInjection returns a RegistryOption that layers the given
+// FaultSpec on top of every transport StartRegistry hands out, so tests can
+// validate the registry's retry/resume behavior against flaky object
+// stores without needing a real S3 outage.
+func WithFaultInjection(spec FaultSpec) RegistryOption {
+	return func(o *RegistryOptions) {
+		o.TransportWrapper = func(rt http.RoundTripper) http.RoundTripper {
+			return WrapTransport(rt, spec)
+		}
+	}
+}
+
+// WrapTransport layers fault injection described by spec on top of the
+// repository's existing transport.
+func (r *Repository) WrapTransport(spec FaultSpec) {
+	r.transport = WrapTransport(r.transport, spec)
+}
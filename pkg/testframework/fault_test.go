@@ -0,0 +1,95 @@
+package testframework
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestFaultScenarioMatches(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/v2/foo/blobs/uploads/1", nil)
+
+	cases := []struct {
+		name string
+		s    FaultScenario
+		want bool
+	}{
+		{"no fault and no latency never matches", FaultScenario{}, false},
+		{"matching method and pattern", FaultScenario{Method: http.MethodPatch, URLPattern: `/blobs/uploads/`, Fault: DropConnection()}, true},
+		{"wrong method", FaultScenario{Method: http.MethodGet, Fault: DropConnection()}, false},
+		{"non-matching pattern", FaultScenario{URLPattern: `/manifests/`, Fault: DropConnection()}, false},
+		{"latency only, no fault", FaultScenario{Latency: time.Millisecond}, true},
+		{"expired duration", FaultScenario{Fault: DropConnection(), Duration: -time.Second}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			s := c.s
+			if s.URLPattern != "" {
+				s.re = regexp.MustCompile(s.URLPattern)
+			}
+			s.start = time.Now()
+			if got := s.matches(req); got != c.want {
+				t.Errorf("matches() = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFaultScenarioEveryNth(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/v2/foo/blobs/uploads/1", nil)
+	s := &FaultScenario{Fault: DropConnection(), EveryNth: 3}
+	s.start = time.Now()
+
+	var matched int
+	for i := 0; i < 9; i++ {
+		if s.matches(req) {
+			matched++
+		}
+	}
+	if matched != 3 {
+		t.Errorf("expected every 3rd request (of 9) to match, got %d matches", matched)
+	}
+}
+
+func TestWrapTransportAppliesLatency(t *testing.T) {
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(nil)), Header: make(http.Header)}, nil
+	})
+
+	const latency = 20 * time.Millisecond
+	rt := WrapTransport(base, FaultSpec{Scenarios: []FaultScenario{{Latency: latency}}})
+
+	start := time.Now()
+	if _, err := rt.RoundTrip(httptest.NewRequest(http.MethodGet, "/v2/foo/manifests/latest", nil)); err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Errorf("expected the request to be delayed by at least %s, took %s", latency, elapsed)
+	}
+}
+
+func TestTruncatingReader(t *testing.T) {
+	r := &truncatingReader{r: ioutil.NopCloser(bytes.NewReader([]byte("hello world"))), remaining: 5}
+
+	buf := make([]byte, 32)
+	n, err := r.Read(buf)
+	if err != nil {
+		t.Fatalf("first Read: %v", err)
+	}
+	if string(buf[:n]) != "hello" {
+		t.Errorf("first Read = %q, want %q", buf[:n], "hello")
+	}
+
+	if _, err := r.Read(buf); err == nil {
+		t.Errorf("expected a second Read past remaining bytes to error, got nil")
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
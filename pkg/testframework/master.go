@@ -7,6 +7,9 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -115,9 +118,19 @@ type Master struct {
 	t               *testing.T
 	container       MasterInterface
 	adminKubeConfig *rest.Config
-	namespaces      []string
+
+	nsMu       sync.Mutex
+	namespaces []string
+
+	oidcOnce   sync.Once
+	oidcIssuer *testOIDCIssuer
 }
 
+// namespaceOwnerLabel is set on every namespace created through
+// Master.CreateProject so that VerifyNoLeaks can find them even across test
+// binary runs, independent of any in-memory bookkeeping.
+const namespaceOwnerLabel = "image-registry-test/owner"
+
 func NewMaster(t *testing.T) *Master {
 	var container MasterInterface
 	var err error
@@ -126,7 +139,10 @@ func NewMaster(t *testing.T) *Master {
 	} else if path, ok := os.LookupEnv("KUBECONFIG"); ok {
 		container, err = StartMasterProcess(path)
 	} else {
-		t.Fatalf("tests should be run with either TEST_KUBECONFIG or KUBECONFIG")
+		// No external cluster was wired up for us: boot a local
+		// control plane instead of requiring the caller to stand up a
+		// real one.
+		container, err = StartEmbeddedMaster(t)
 	}
 	if err != nil {
 		t.Fatal(err)
@@ -171,7 +187,7 @@ func (m *Master) WaitForRoles() error {
 
 func (m *Master) Close() {
 	if kubeClient, err := kubeclient.NewForConfig(m.AdminKubeConfig()); err == nil {
-		for _, ns := range m.namespaces {
+		for _, ns := range m.takeNamespaces() {
 			if err := kubeClient.CoreV1().Namespaces().Delete(context.Background(), ns, metav1.DeleteOptions{}); err != nil {
 				m.t.Logf("failed to cleanup namespace %s: %v", ns, err)
 			}
@@ -183,6 +199,59 @@ func (m *Master) Close() {
 	}
 }
 
+func (m *Master) addNamespace(ns string) {
+	m.nsMu.Lock()
+	defer m.nsMu.Unlock()
+	m.namespaces = append(m.namespaces, ns)
+}
+
+func (m *Master) removeNamespace(ns string) {
+	m.nsMu.Lock()
+	defer m.nsMu.Unlock()
+	for i, existing := range m.namespaces {
+		if existing == ns {
+			m.namespaces = append(m.namespaces[:i], m.namespaces[i+1:]...)
+			return
+		}
+	}
+}
+
+func (m *Master) takeNamespaces() []string {
+	m.nsMu.Lock()
+	defer m.nsMu.Unlock()
+	namespaces := m.namespaces
+	m.namespaces = nil
+	return namespaces
+}
+
+// VerifyNoLeaks lists every namespace carrying the image-registry-test/owner
+// label and fails t if any survive. It is meant to be called at suite
+// teardown, after every individual ProjectScope's own cleanup has already
+// run, to catch leaks that per-test cleanup cannot: namespaces left behind
+// by a killed test binary, or whose per-test deletion failed. Namespaces
+// created outside of CreateProject carry no such label and are not checked.
+func (m *Master) VerifyNoLeaks(t *testing.T) {
+	kubeClient, err := kubeclient.NewForConfig(m.AdminKubeConfig())
+	if err != nil {
+		t.Fatalf("failed to build a client to verify namespace cleanup: %v", err)
+	}
+
+	namespaces, err := kubeClient.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{
+		LabelSelector: namespaceOwnerLabel,
+	})
+	if err != nil {
+		t.Fatalf("failed to list namespaces to verify cleanup: %v", err)
+	}
+
+	var leaked []string
+	for _, ns := range namespaces.Items {
+		leaked = append(leaked, ns.Name)
+	}
+	if len(leaked) > 0 {
+		t.Fatalf("namespaces leaked across tests: %v", leaked)
+	}
+}
+
 func (m *Master) AdminKubeConfig() *rest.Config {
 	if m.adminKubeConfig != nil {
 		return m.adminKubeConfig
@@ -204,6 +273,7 @@ func (m *Master) StartRegistry(t *testing.T, options ...RegistryOption) *Registr
 		t:        t,
 		listener: ln,
 		closeFn:  closeFn,
+		options:  resolveRegistryOptions(options),
 	}
 }
 
@@ -235,7 +305,92 @@ func (m *Master) GrantPrunerRole(user *User) {
 	}
 }
 
-func (m *Master) CreateProject(namespace, user string) *projectapiv1.Project {
-	m.namespaces = append(m.namespaces, namespace)
-	return CreateProject(m.t, m.AdminKubeConfig(), namespace, user)
+// CreateProject creates a namespace-backed project owned by user and returns
+// a ProjectScope that registers its own cleanup on t via t.Cleanup. Passing
+// the subtest's *testing.T (rather than the Master's own) is what makes this
+// safe to call from parallel subtests: each ProjectScope cleans up exactly
+// the namespace it created, on the test that created it.
+func (m *Master) CreateProject(t *testing.T, namespace, user string) *ProjectScope {
+	t.Helper()
+
+	project := CreateProject(t, m.AdminKubeConfig(), namespace, user)
+	m.addNamespace(namespace)
+
+	kubeClient, err := kubeclient.NewForConfig(m.AdminKubeConfig())
+	if err != nil {
+		t.Fatalf("failed to label namespace %s for leak detection: %v", namespace, err)
+	}
+	if err := labelNamespaceOwner(kubeClient, namespace, sanitizeLabelValue(t.Name())); err != nil {
+		// Not logged-and-ignored: an unlabeled namespace is invisible to
+		// VerifyNoLeaks, which would defeat the point of this whole
+		// leak-detection mechanism.
+		t.Fatalf("failed to label namespace %s for leak detection: %v", namespace, err)
+	}
+
+	scope := &ProjectScope{
+		m:         m,
+		t:         t,
+		Namespace: namespace,
+		Project:   project,
+	}
+	t.Cleanup(scope.cleanup)
+	return scope
+}
+
+// labelValueInvalidChars matches everything Kubernetes label values forbid
+// ([-A-Za-z0-9_.] is the only allowed charset).
+var labelValueInvalidChars = regexp.MustCompile(`[^-A-Za-z0-9_.]`)
+
+// sanitizeLabelValue turns s into a valid Kubernetes label value: t.Name()
+// for a parallel subtest is always "Parent/child", and "/" alone makes it
+// invalid, so this must run before using a test name as a label value.
+func sanitizeLabelValue(s string) string {
+	s = labelValueInvalidChars.ReplaceAllString(s, "_")
+	s = strings.Trim(s, "-_.")
+	if len(s) > 63 {
+		s = strings.Trim(s[:63], "-_.")
+	}
+	if s == "" {
+		s = "unknown"
+	}
+	return s
+}
+
+func labelNamespaceOwner(kubeClient kubeclient.Interface, namespace, owner string) error {
+	ns, err := kubeClient.CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	if ns.Labels == nil {
+		ns.Labels = map[string]string{}
+	}
+	ns.Labels[namespaceOwnerLabel] = owner
+	_, err = kubeClient.CoreV1().Namespaces().Update(context.Background(), ns, metav1.UpdateOptions{})
+	return err
+}
+
+// ProjectScope is the handle returned by Master.CreateProject. It owns the
+// lifecycle of the namespace it was created for: cleanup is registered
+// against the owning test via t.Cleanup, so a leaked namespace from one
+// parallel subtest can never be left for another subtest (or Master.Close)
+// to race over.
+type ProjectScope struct {
+	m *Master
+	t *testing.T
+
+	Namespace string
+	Project   *projectapiv1.Project
+}
+
+func (s *ProjectScope) cleanup() {
+	s.m.removeNamespace(s.Namespace)
+
+	kubeClient, err := kubeclient.NewForConfig(s.m.AdminKubeConfig())
+	if err != nil {
+		s.t.Logf("failed to cleanup namespace %s: %v", s.Namespace, err)
+		return
+	}
+	if err := kubeClient.CoreV1().Namespaces().Delete(context.Background(), s.Namespace, metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+		s.t.Logf("failed to cleanup namespace %s: %v", s.Namespace, err)
+	}
 }
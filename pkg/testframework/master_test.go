@@ -0,0 +1,41 @@
+package testframework
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// validLabelValueRE mirrors the Kubernetes label value validation rule, to
+// check sanitizeLabelValue's output independent of its own implementation.
+var validLabelValueRE = regexp.MustCompile(`^([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+
+func TestSanitizeLabelValue(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"parallel subtest name", "TestFoo/bar_baz", "TestFoo_bar_baz"},
+		{"nested parallel subtest name", "TestFoo/bar/baz", "TestFoo_bar_baz"},
+		{"already valid", "plain-value.1", "plain-value.1"},
+		{"empty", "", "unknown"},
+		{"only invalid chars", "///", "unknown"},
+		{"long but valid", strings.Repeat("a", 100), strings.Repeat("a", 63)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := sanitizeLabelValue(c.in)
+			if got != c.want {
+				t.Errorf("sanitizeLabelValue(%q) = %q, want %q", c.in, got, c.want)
+			}
+			if len(got) > 63 {
+				t.Errorf("sanitizeLabelValue(%q) = %q, exceeds 63 chars", c.in, got)
+			}
+			if !validLabelValueRE.MatchString(got) {
+				t.Errorf("sanitizeLabelValue(%q) = %q is not a valid label value", c.in, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package testframework
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testOIDCIssuer is a minimal in-process stand-in for a dex/pinniped-style
+// OIDC issuer: it serves a discovery document and mints RS256 ID tokens for
+// OIDCAuth, signed with a key it generates itself.
+type testOIDCIssuer struct {
+	server     *httptest.Server
+	signingKey *rsa.PrivateKey
+}
+
+var (
+	oidcIssuersMu sync.Mutex
+	oidcIssuers   = map[string]*testOIDCIssuer{}
+)
+
+func startTestOIDCIssuer(t *testing.T) (*testOIDCIssuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OIDC signing key: %v", err)
+	}
+
+	issuer := &testOIDCIssuer{signingKey: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64URLEncode(key.PublicKey.N.Bytes()),
+				"e":   base64URLEncode(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	issuer.server = httptest.NewTLSServer(mux)
+
+	t.Cleanup(issuer.server.Close)
+
+	oidcIssuersMu.Lock()
+	oidcIssuers[issuer.server.URL] = issuer
+	oidcIssuersMu.Unlock()
+	t.Cleanup(func() {
+		oidcIssuersMu.Lock()
+		delete(oidcIssuers, issuer.server.URL)
+		oidcIssuersMu.Unlock()
+	})
+
+	return issuer, nil
+}
+
+// OIDCIssuerURL lazily starts this master's in-test OIDC issuer and returns
+// its URL, for use as the default OIDCAuth.IssuerURL.
+func (m *Master) OIDCIssuerURL() string {
+	m.oidcOnce.Do(func() {
+		issuer, err := startTestOIDCIssuer(m.t)
+		if err != nil {
+			m.t.Fatalf("failed to start the in-test OIDC issuer: %v", err)
+		}
+		m.oidcIssuer = issuer
+	})
+	return m.oidcIssuer.server.URL
+}
+
+// MintOIDCToken mints an RS256 ID token for username from the in-test
+// issuer previously started at issuerURL (via Master.OIDCIssuerURL).
+func MintOIDCToken(issuerURL, username string) (string, error) {
+	oidcIssuersMu.Lock()
+	issuer, ok := oidcIssuers[issuerURL]
+	oidcIssuersMu.Unlock()
+	if !ok {
+		return "", fmt.Errorf("no in-test OIDC issuer is running at %s", issuerURL)
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss": issuerURL,
+		"sub": username,
+		"aud": "image-registry",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64URLEncode(header) + "." + base64URLEncode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, issuer.signingKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign OIDC token for %s: %v", username, err)
+	}
+
+	return signingInput + "." + base64URLEncode(signature), nil
+}
+
+func base64URLEncode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,70 @@
+package testframework
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMintOIDCToken(t *testing.T) {
+	issuer, err := startTestOIDCIssuer(t)
+	if err != nil {
+		t.Fatalf("startTestOIDCIssuer: %v", err)
+	}
+
+	token, err := MintOIDCToken(issuer.server.URL, "alice")
+	if err != nil {
+		t.Fatalf("MintOIDCToken: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token has %d parts, want 3 (header.claims.signature)", len(parts))
+	}
+
+	var header map[string]string
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		t.Fatalf("failed to decode header: %v", err)
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		t.Fatalf("failed to unmarshal header: %v", err)
+	}
+	if header["alg"] != "RS256" {
+		t.Errorf("header alg = %q, want %q", header["alg"], "RS256")
+	}
+
+	var claims map[string]interface{}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatalf("failed to decode claims: %v", err)
+	}
+	if err := json.Unmarshal(claimsJSON, &claims); err != nil {
+		t.Fatalf("failed to unmarshal claims: %v", err)
+	}
+	if claims["sub"] != "alice" {
+		t.Errorf("claims[sub] = %v, want %q", claims["sub"], "alice")
+	}
+	if claims["iss"] != issuer.server.URL {
+		t.Errorf("claims[iss] = %v, want %q", claims["iss"], issuer.server.URL)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatalf("failed to decode signature: %v", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&issuer.signingKey.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("token signature does not verify against the issuer's signing key: %v", err)
+	}
+}
+
+func TestMintOIDCTokenUnknownIssuer(t *testing.T) {
+	if _, err := MintOIDCToken("https://nowhere.example.invalid", "alice"); err == nil {
+		t.Error("expected an error minting a token for an issuer that was never started, got nil")
+	}
+}
@@ -0,0 +1,214 @@
+package testframework
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	imageclientv1 "github.com/openshift/client-go/image/clientset/versioned/typed/image/v1"
+)
+
+// PrunerHarness drives `oc adm prune images`-style flows against a running
+// Registry so tests can assert which blobs and manifests survive a prune,
+// without shelling out to the oc binary.
+type PrunerHarness struct {
+	t    *testing.T
+	m    *Master
+	repo *Repository
+
+	// KeepYoungerThan and KeepTagRevisions mirror the `oc adm prune
+	// images --keep-younger-than` / `--keep-tag-revisions` flags.
+	KeepYoungerThan  time.Duration
+	KeepTagRevisions int
+}
+
+// NewPrunerHarness builds a harness that runs pruning operations against
+// repoName, using user's transport for registry calls. user must already
+// hold the system:image-pruner role (see Master.GrantPrunerRole).
+func (m *Master) NewPrunerHarness(t *testing.T, registry *Registry, user *User, repoName string) *PrunerHarness {
+	return &PrunerHarness{
+		t:                t,
+		m:                m,
+		repo:             registry.Repository(t, repoName, user),
+		KeepYoungerThan:  60 * time.Minute,
+		KeepTagRevisions: 3,
+	}
+}
+
+// Digests enumerates every manifest and layer blob digest currently
+// reachable from a tag in the repository, via the distribution API, using
+// the harness user's transport.
+func (h *PrunerHarness) Digests() ([]digest.Digest, error) {
+	ctx := context.Background()
+
+	tags, err := h.repo.Tags(ctx).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for %s: %v", h.repo.RepoName(), err)
+	}
+
+	manifests, err := h.repo.Manifests(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get a manifest service for %s: %v", h.repo.RepoName(), err)
+	}
+
+	seen := map[digest.Digest]struct{}{}
+	var digests []digest.Digest
+	add := func(d digest.Digest) {
+		if _, ok := seen[d]; ok {
+			return
+		}
+		seen[d] = struct{}{}
+		digests = append(digests, d)
+	}
+
+	for _, tag := range tags {
+		desc, err := h.repo.Tags(ctx).Get(ctx, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve tag %s: %v", tag, err)
+		}
+		add(desc.Digest)
+
+		manifest, err := manifests.Get(ctx, desc.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch manifest %s: %v", desc.Digest, err)
+		}
+		references := manifest.References()
+		for _, ref := range references {
+			add(ref.Digest)
+		}
+	}
+
+	return digests, nil
+}
+
+// Prune determines which digests fall outside the harness's
+// KeepYoungerThan / KeepTagRevisions window from the ImageStream status,
+// then removes each one through the registry's admin blob-delete endpoint
+// (the same endpoint `oc adm prune images` drives) using the harness
+// user's transport, rather than only deleting Image API objects directly.
+// It returns the digests it removed.
+func (h *PrunerHarness) Prune() ([]digest.Digest, error) {
+	namespace, streamName, err := splitRepoName(h.repo.RepoName())
+	if err != nil {
+		return nil, err
+	}
+
+	imageClient := imageclientv1.NewForConfigOrDie(h.m.AdminKubeConfig())
+
+	stream, err := imageClient.ImageStreams(namespace).Get(context.Background(), streamName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get imagestream %s/%s: %v", namespace, streamName, err)
+	}
+
+	cutoff := time.Now().Add(-h.KeepYoungerThan)
+
+	var pruned []digest.Digest
+	for _, tagEvents := range stream.Status.Tags {
+		for i, event := range tagEvents.Items {
+			if shouldKeepTagEvent(i, event.Created.Time, h.KeepTagRevisions, cutoff) {
+				continue
+			}
+
+			dgst := digest.Digest(event.Image)
+			if err := h.pruneBlob(dgst); err != nil {
+				return pruned, err
+			}
+			if err := imageClient.Images().Delete(context.Background(), event.Image, metav1.DeleteOptions{}); err != nil {
+				return pruned, fmt.Errorf("failed to prune image %s (tag %s): %v", event.Image, tagEvents.Tag, err)
+			}
+			pruned = append(pruned, dgst)
+		}
+	}
+
+	return pruned, nil
+}
+
+// pruneBlob calls the registry's admin blob-delete endpoint for dgst using
+// the harness user's transport. The user must hold the system:image-pruner
+// role (see Master.GrantPrunerRole) for the registry to honor the request.
+func (h *PrunerHarness) pruneBlob(dgst digest.Digest) error {
+	req, err := http.NewRequest(http.MethodDelete, h.repo.BaseURL()+"/admin/blobs/"+dgst.String(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.repo.Transport().RoundTrip(req)
+	if err != nil {
+		return fmt.Errorf("failed to prune blob %s: %v", dgst, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusAccepted, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("registry refused to prune blob %s: %s", dgst, resp.Status)
+	}
+}
+
+// AssertRemaining fails the test unless exactly the given digests are still
+// present in the repository.
+func (h *PrunerHarness) AssertRemaining(want ...digest.Digest) {
+	h.t.Helper()
+
+	got, err := h.Digests()
+	if err != nil {
+		h.t.Fatalf("failed to enumerate remaining digests: %v", err)
+	}
+
+	wantSet := map[digest.Digest]struct{}{}
+	for _, d := range want {
+		wantSet[d] = struct{}{}
+	}
+	gotSet := map[digest.Digest]struct{}{}
+	for _, d := range got {
+		gotSet[d] = struct{}{}
+	}
+
+	for d := range wantSet {
+		if _, ok := gotSet[d]; !ok {
+			h.t.Errorf("expected digest %s to remain after prune, but it is gone", d)
+		}
+	}
+	for d := range gotSet {
+		if _, ok := wantSet[d]; !ok {
+			h.t.Errorf("expected digest %s to be pruned, but it remains", d)
+		}
+	}
+}
+
+// SimulateOrphan deletes the Image object referencing dgst out-of-band
+// (bypassing the registry), so tests can cover the "blob referenced only by
+// a deleted image" case end-to-end.
+func (h *PrunerHarness) SimulateOrphan(dgst digest.Digest) error {
+	imageClient := imageclientv1.NewForConfigOrDie(h.m.AdminKubeConfig())
+	if err := imageClient.Images().Delete(context.Background(), dgst.String(), metav1.DeleteOptions{}); err != nil {
+		return fmt.Errorf("failed to delete image %s: %v", dgst, err)
+	}
+	return nil
+}
+
+// shouldKeepTagEvent reports whether a tag revision at index i (0 is the
+// most recent) should survive a prune, mirroring `oc adm prune images`:
+// the KeepTagRevisions most recent revisions are always kept, and any
+// revision created after cutoff is kept regardless of its index.
+func shouldKeepTagEvent(i int, created time.Time, keepTagRevisions int, cutoff time.Time) bool {
+	return i < keepTagRevisions || created.After(cutoff)
+}
+
+// splitRepoName splits a "namespace/imagestream" repository name, the form
+// Repository.RepoName() always returns for registry-backed repositories.
+func splitRepoName(repoName string) (namespace, streamName string, err error) {
+	parts := strings.SplitN(repoName, "/", 2)
+	if len(parts) != 2 {
+		return "", "", fmt.Errorf("invalid repository name %q: expected namespace/imagestream", repoName)
+	}
+	return parts[0], parts[1], nil
+}
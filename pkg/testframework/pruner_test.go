@@ -0,0 +1,67 @@
+package testframework
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShouldKeepTagEvent(t *testing.T) {
+	now := time.Now()
+	cutoff := now.Add(-60 * time.Minute)
+
+	cases := []struct {
+		name             string
+		index            int
+		created          time.Time
+		keepTagRevisions int
+		want             bool
+	}{
+		{"within keep-tag-revisions window, old", 0, now.Add(-2 * time.Hour), 3, true},
+		{"last index inside window, old", 2, now.Add(-2 * time.Hour), 3, true},
+		{"outside window, old enough to prune", 3, now.Add(-2 * time.Hour), 3, false},
+		{"outside window, but younger than cutoff", 5, now.Add(-time.Minute), 3, true},
+		{"outside window, exactly at cutoff", 3, cutoff, 3, false},
+		{"keepTagRevisions zero, still young enough", 0, now, 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := shouldKeepTagEvent(c.index, c.created, c.keepTagRevisions, cutoff); got != c.want {
+				t.Errorf("shouldKeepTagEvent(%d, %s, %d, cutoff) = %v, want %v", c.index, c.created, c.keepTagRevisions, got, c.want)
+			}
+		})
+	}
+}
+
+func TestSplitRepoName(t *testing.T) {
+	cases := []struct {
+		name           string
+		in             string
+		wantNamespace  string
+		wantStreamName string
+		wantErr        bool
+	}{
+		{"valid", "myproject/myimage", "myproject", "myimage", false},
+		{"nested stream name keeps remainder together", "myproject/my/image", "myproject", "my/image", false},
+		{"missing slash", "myimage", "", "", true},
+		{"empty", "", "", "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			namespace, streamName, err := splitRepoName(c.in)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("splitRepoName(%q): expected an error, got none", c.in)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("splitRepoName(%q): %v", c.in, err)
+			}
+			if namespace != c.wantNamespace || streamName != c.wantStreamName {
+				t.Errorf("splitRepoName(%q) = (%q, %q), want (%q, %q)", c.in, namespace, streamName, c.wantNamespace, c.wantStreamName)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package testframework
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/reference"
+
+	"k8s.io/client-go/rest"
+)
+
+// RegistryOptions configures a Registry started by Master.StartRegistry.
+type RegistryOptions struct {
+	// TransportWrapper, if set, wraps the http.RoundTripper handed to
+	// every *Repository this Registry constructs, e.g. to layer fault
+	// injection on top of it via WithFaultInjection.
+	TransportWrapper func(http.RoundTripper) http.RoundTripper
+}
+
+// RegistryOption mutates a RegistryOptions. Passed to Master.StartRegistry.
+type RegistryOption func(*RegistryOptions)
+
+func resolveRegistryOptions(options []RegistryOption) RegistryOptions {
+	var o RegistryOptions
+	for _, apply := range options {
+		apply(&o)
+	}
+	return o
+}
+
+// Registry is a running instance of the image registry under test.
+type Registry struct {
+	t        *testing.T
+	listener net.Listener
+	closeFn  func()
+	options  RegistryOptions
+}
+
+// BaseURL is the https base URL the registry is listening on.
+func (r *Registry) BaseURL() string {
+	return "https://" + r.listener.Addr().String()
+}
+
+// Close stops the registry.
+func (r *Registry) Close() {
+	if r.closeFn != nil {
+		r.closeFn()
+	}
+}
+
+// Repository builds a *Repository for repoName authenticated as user,
+// applying any TransportWrapper configured via Master.StartRegistry's
+// RegistryOptions (e.g. WithFaultInjection) on top of user's transport.
+func (r *Registry) Repository(t *testing.T, repoName string, user *User) *Repository {
+	t.Helper()
+
+	rt, err := rest.TransportFor(user.KubeConfig())
+	if err != nil {
+		t.Fatalf("failed to build a transport for user %s: %v", user.Name, err)
+	}
+	if r.options.TransportWrapper != nil {
+		rt = r.options.TransportWrapper(rt)
+	}
+
+	named, err := reference.WithName(repoName)
+	if err != nil {
+		t.Fatalf("invalid repository name %q: %v", repoName, err)
+	}
+
+	repo, err := distribution.NewRepository(named, r.BaseURL(), rt)
+	if err != nil {
+		t.Fatalf("failed to construct a repository client for %q: %v", repoName, err)
+	}
+
+	return &Repository{
+		Repository: repo,
+		baseURL:    r.BaseURL(),
+		repoName:   repoName,
+		transport:  rt,
+	}
+}